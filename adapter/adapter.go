@@ -46,14 +46,43 @@ var channelFactories = map[string]adaptercommon.FactoryCreator{
 	globals.GroqChannelType:     openai.NewChatInstanceFromConfig, // openai format
 }
 
+// NewFactory creates the chat instance of the channel by its type
+func NewFactory(conf globals.ChannelConfig) (adaptercommon.Factory, error) {
+	if factory, ok := channelFactories[conf.GetType()]; ok {
+		return factory(conf), nil
+	}
+
+	return nil, fmt.Errorf("unknown channel type %s (channel #%d)", conf.GetType(), conf.GetId())
+}
+
 func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
 	props.Model = conf.GetModelReflect(props.OriginalModel)
 	props.Proxy = conf.GetProxy()
 
-	factoryType := conf.GetType()
-	if factory, ok := channelFactories[factoryType]; ok {
-		return factory(conf).CreateStreamChatRequest(props, hook)
+	factory, err := NewFactory(conf)
+	if err != nil {
+		return err
+	}
+
+	return factory.CreateStreamChatRequest(props, hook)
+}
+
+// NewRawChatRequest sends the chat request through the channel once, without retrying or processing the error
+func NewRawChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return createChatRequest(conf, props, hook)
+}
+
+// ListModels requests the model list from the upstream of the channel
+func ListModels(conf globals.ChannelConfig) ([]string, error) {
+	factory, err := NewFactory(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := factory.(adaptercommon.ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("channel type %s does not support model discovery", conf.GetType())
 	}
 
-	return fmt.Errorf("unknown channel type %s (channel #%d)", conf.GetType(), conf.GetId())
+	return lister.ListModels(conf.GetProxy())
 }
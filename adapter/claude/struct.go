@@ -3,6 +3,7 @@ package claude
 import (
 	factory "chat/adapter/common"
 	"chat/globals"
+	"fmt"
 )
 
 type ChatInstance struct {
@@ -31,3 +32,7 @@ func (c *ChatInstance) GetEndpoint() string {
 func (c *ChatInstance) GetApiKey() string {
 	return c.ApiKey
 }
+
+func (c *ChatInstance) ListModels(proxy globals.ProxyConfig) ([]string, error) {
+	return factory.ListOpenAIModels(fmt.Sprintf("%s/v1/models?limit=1000", c.GetEndpoint()), c.GetChatHeaders(), proxy)
+}
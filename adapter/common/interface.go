@@ -9,3 +9,8 @@ type Factory interface {
 }
 
 type FactoryCreator func(globals.ChannelConfig) Factory
+
+// ModelLister is implemented by the factories which support upstream model discovery
+type ModelLister interface {
+	ListModels(proxy globals.ProxyConfig) ([]string, error)
+}
@@ -0,0 +1,41 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type ModelListResponse struct {
+	Data []struct {
+		Id string `json:"id"`
+	} `json:"data"`
+	Error interface{} `json:"error,omitempty"`
+}
+
+// ListOpenAIModels requests the openai compatible model list (`{"data": [{"id": "..."}]}`)
+func ListOpenAIModels(uri string, headers map[string]string, proxy globals.ProxyConfig) ([]string, error) {
+	data, err := utils.HttpRaw(uri, http.MethodGet, headers, nil, []globals.ProxyConfig{proxy})
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := utils.Unmarshal[ModelListResponse](data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse model list: %s", utils.Extract(string(data), 200, "..."))
+	}
+
+	if form.Error != nil && len(form.Data) == 0 {
+		return nil, fmt.Errorf("upstream error: %s", utils.Marshal(form.Error))
+	}
+
+	models := make([]string, 0, len(form.Data))
+	for _, item := range form.Data {
+		if id := strings.TrimSpace(item.Id); len(id) > 0 {
+			models = append(models, id)
+		}
+	}
+	return models, nil
+}
@@ -49,6 +49,10 @@ func (c *ChatInstance) GetChatEndpoint() string {
 	return fmt.Sprintf("%s/chat/completions", c.GetEndpoint())
 }
 
+func (c *ChatInstance) ListModels(proxy globals.ProxyConfig) ([]string, error) {
+	return adaptercommon.ListOpenAIModels(fmt.Sprintf("%s/models", c.GetEndpoint()), c.GetHeader(), proxy)
+}
+
 func (c *ChatInstance) GetChatBody(props *adaptercommon.ChatProps, stream bool) interface{} {
 	messages := props.Message
 	// because of deepseek first message must be user role
@@ -0,0 +1,141 @@
+package adapter
+
+import (
+	"chat/globals"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	metricsSmoothing = 0.3              // weight of the latest sample in the moving average
+	metricsPenalty   = 10 * time.Second // min sample of a failed request, so that broken channels sink in latency routing
+)
+
+type ModelMetrics struct {
+	FirstToken float64 `json:"first_token"` // moving average of time to first token (ms)
+	Duration   float64 `json:"duration"`    // moving average of request duration (ms)
+	Samples    int64   `json:"samples"`
+}
+
+type MetricsManager struct {
+	mutex    sync.RWMutex
+	models   map[string]*ModelMetrics
+	inflight map[int]int64
+}
+
+type Measurement struct {
+	id      int
+	model   string
+	start   time.Time
+	first   time.Time
+	manager *MetricsManager
+}
+
+var MetricsInstance = NewMetricsManager()
+
+func NewMetricsManager() *MetricsManager {
+	return &MetricsManager{
+		models:   map[string]*ModelMetrics{},
+		inflight: map[int]int64{},
+	}
+}
+
+func getMetricsKey(id int, model string) string {
+	return fmt.Sprintf("%d:%s", id, model)
+}
+
+func smooth(previous float64, sample float64, samples int64) float64 {
+	if samples == 0 {
+		return sample
+	}
+	return previous*(1-metricsSmoothing) + sample*metricsSmoothing
+}
+
+// Begin marks a request in flight on the channel and starts measuring it
+func (m *MetricsManager) Begin(id int, model string) *Measurement {
+	m.mutex.Lock()
+	m.inflight[id]++
+	m.mutex.Unlock()
+
+	return &Measurement{
+		id:      id,
+		model:   model,
+		start:   time.Now(),
+		manager: m,
+	}
+}
+
+// GetFirstToken returns the moving average of time to first token (ms) and whether it has been measured
+func (m *MetricsManager) GetFirstToken(id int, model string) (float64, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if metrics, ok := m.models[getMetricsKey(id, model)]; ok && metrics.Samples > 0 {
+		return metrics.FirstToken, true
+	}
+	return 0, false
+}
+
+func (m *MetricsManager) GetInFlight(id int) int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.inflight[id]
+}
+
+func (m *MetricsManager) GetModelMetrics(id int, model string) ModelMetrics {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if metrics, ok := m.models[getMetricsKey(id, model)]; ok {
+		return *metrics
+	}
+	return ModelMetrics{}
+}
+
+// Hook wraps the chunk hook to catch the first non-empty chunk
+func (s *Measurement) Hook(hook globals.Hook) globals.Hook {
+	return func(data *globals.Chunk) error {
+		if s.first.IsZero() && data != nil && !data.IsEmpty() {
+			s.first = time.Now()
+		}
+		return hook(data)
+	}
+}
+
+// Done finishes the measurement, failed requests are sampled with a penalty
+// and the requests stopped by the user before the first token are skipped
+func (s *Measurement) Done(err error) {
+	m := s.manager
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.inflight[s.id]--; m.inflight[s.id] <= 0 {
+		delete(m.inflight, s.id)
+	}
+
+	duration := time.Since(s.start)
+	first := s.first.Sub(s.start)
+
+	if IsAvailableError(err) {
+		if duration < metricsPenalty {
+			duration = metricsPenalty
+		}
+		first = duration
+	} else if s.first.IsZero() {
+		return
+	}
+
+	key := getMetricsKey(s.id, s.model)
+	metrics, ok := m.models[key]
+	if !ok {
+		metrics = &ModelMetrics{}
+		m.models[key] = metrics
+	}
+
+	metrics.FirstToken = smooth(metrics.FirstToken, float64(first.Milliseconds()), metrics.Samples)
+	metrics.Duration = smooth(metrics.Duration, float64(duration.Milliseconds()), metrics.Samples)
+	metrics.Samples++
+}
@@ -39,3 +39,7 @@ func NewChatInstanceFromConfig(conf globals.ChannelConfig) factory.Factory {
 		conf.GetRandomSecret(),
 	)
 }
+
+func (c *ChatInstance) ListModels(proxy globals.ProxyConfig) ([]string, error) {
+	return factory.ListOpenAIModels(fmt.Sprintf("%s/v1/models", c.GetEndpoint()), c.GetHeader(), proxy)
+}
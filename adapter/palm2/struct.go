@@ -3,6 +3,10 @@ package palm2
 import (
 	factory "chat/adapter/common"
 	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"net/http"
+	"strings"
 )
 
 type ChatInstance struct {
@@ -31,3 +35,31 @@ func NewChatInstanceFromConfig(conf globals.ChannelConfig) factory.Factory {
 		conf.GetRandomSecret(),
 	)
 }
+
+func (c *ChatInstance) ListModels(proxy globals.ProxyConfig) ([]string, error) {
+	// the key is sent in the header, so that it would not be exposed in the url of the request error
+	uri := fmt.Sprintf("%s/v1beta/models?pageSize=1000", c.GetEndpoint())
+	data, err := utils.HttpRaw(uri, http.MethodGet, map[string]string{
+		"x-goog-api-key": c.GetApiKey(),
+	}, nil, []globals.ProxyConfig{proxy})
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := utils.Unmarshal[ModelListResponse](data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse model list: %s", utils.Extract(string(data), 200, "..."))
+	}
+
+	if form.Error != nil {
+		return nil, fmt.Errorf("gemini error: %s (status: %s)", form.Error.Message, form.Error.Status)
+	}
+
+	var models []string
+	for _, item := range form.Models {
+		if utils.Contains("generateContent", item.SupportedGenerationMethods) || utils.Contains("predict", item.SupportedGenerationMethods) {
+			models = append(models, strings.TrimPrefix(item.Name, "models/"))
+		}
+	}
+	return models, nil
+}
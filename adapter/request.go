@@ -25,7 +25,9 @@ func isQPSOverLimit(model string, err error) bool {
 }
 
 func NewChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
-	err := createChatRequest(conf, props, hook)
+	measurement := MetricsInstance.Begin(conf.GetId(), props.OriginalModel)
+	err := createChatRequest(conf, props, measurement.Hook(hook))
+	measurement.Done(err)
 
 	retries := conf.GetRetry()
 	props.Current++
@@ -20,6 +20,9 @@ export type Channel = {
   mapper: string;
   state: boolean;
   group?: string[];
+  cost?: number;
+  limit?: ChannelRateLimit;
+  secret_limit?: ChannelRateLimit;
   proxy?: {
     proxy: string;
     proxy_type: number;
@@ -28,6 +31,24 @@ export type Channel = {
   };
   first_message_as_user?: boolean;
   merge_consecutive_user_messages?: boolean;
+  health?: ChannelHealth;
+};
+
+export type ChannelRateLimit = {
+  rpm: number;
+  tpm: number;
+  concurrency: number;
+};
+
+export type ChannelHealth = {
+  state: "closed" | "open" | "half-open";
+  requests: number;
+  error_rate: number;
+  consecutive_failures: number;
+  last_error: string;
+  last_error_at: number;
+  last_success_at: number;
+  open_until: number;
 };
 
 export enum proxyType {
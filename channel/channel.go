@@ -39,6 +39,10 @@ func (c *Channel) GetWeight() int {
 	return c.Weight
 }
 
+func (c *Channel) GetCost() float32 {
+	return c.Cost
+}
+
 func (c *Channel) GetModels() []string {
 	return c.Models
 }
@@ -1,6 +1,7 @@
 package channel
 
 import (
+	"chat/adapter"
 	"chat/utils"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -52,10 +53,20 @@ func DeactivateChannel(c *gin.Context) {
 	})
 }
 
+type ChannelView struct {
+	*Channel
+	Health HealthSnapshot `json:"health"`
+}
+
 func GetChannelList(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status": true,
-		"data":   ConduitInstance.Sequence,
+		"data": utils.Each(ConduitInstance.Sequence, func(channel *Channel) ChannelView {
+			return ChannelView{
+				Channel: channel,
+				Health:  HealthInstance.GetSnapshot(channel.GetId()),
+			}
+		}),
 	})
 }
 
@@ -200,3 +211,152 @@ func UpdatePlanConfig(c *gin.Context) {
 		"error":  utils.GetError(state),
 	})
 }
+
+func GetRoutingConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data": gin.H{
+			"rules": RoutingInstance.GetRules(),
+		},
+	})
+}
+
+func UpdateRoutingConfig(c *gin.Context) {
+	var config RoutingManager
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	state := RoutingInstance.UpdateConfig(&config)
+	c.JSON(http.StatusOK, gin.H{
+		"status": state == nil,
+		"error":  utils.GetError(state),
+	})
+}
+
+type SecretForm struct {
+	Hash string `json:"hash" binding:"required"`
+}
+
+func GetSecretList(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data":   SecretInstance.ListSecrets(channel),
+	})
+}
+
+func handleSecret(c *gin.Context, action func(channel *Channel, hash string) error) {
+	var form SecretForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	state := action(channel, form.Hash)
+	c.JSON(http.StatusOK, gin.H{
+		"status": state == nil,
+		"error":  utils.GetError(state),
+	})
+}
+
+func ActivateSecret(c *gin.Context) {
+	handleSecret(c, func(channel *Channel, hash string) error {
+		return SecretInstance.SetStatus(channel, hash, SecretActive)
+	})
+}
+
+func DeactivateSecret(c *gin.Context) {
+	handleSecret(c, func(channel *Channel, hash string) error {
+		return SecretInstance.SetStatus(channel, hash, SecretDisabled)
+	})
+}
+
+func CheckSecret(c *gin.Context) {
+	handleSecret(c, SecretInstance.Check)
+}
+
+func PurgeSecrets(c *gin.Context) {
+	id := c.Param("id")
+	state := ConduitInstance.PurgeSecrets(utils.ParseInt(id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": state == nil,
+		"error":  utils.GetError(state),
+	})
+}
+
+func TestChannel(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, RunChannelTest(channel, c.Query("model")))
+}
+
+func GetChannelModels(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	instance, err := channel.WithAvailableSecret()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	models, err := adapter.ListModels(instance)
+	if err = instance.MaskSecret(err); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data":   models,
+	})
+}
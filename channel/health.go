@@ -0,0 +1,327 @@
+package channel
+
+import (
+	"chat/globals"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	HealthClosed   = "closed"
+	HealthOpen     = "open"
+	HealthHalfOpen = "half-open"
+)
+
+var (
+	healthWindowSize          = 20               // rolling window of recent results
+	healthMinRequests         = 10               // min requests in window before the error rate is considered
+	healthErrorRateThreshold  = float32(0.5)     // open the circuit if the error rate reaches this value
+	healthFailureThreshold    = 5                // open the circuit after these consecutive failures
+	healthOpenDuration        = 30 * time.Second // initial cool down before probing
+	healthMaxOpenDuration     = 10 * time.Minute // cool down is doubled on each failed probe up to this value
+	healthHalfOpenProbeLimit  = 1                // concurrent probe requests while half-open
+	healthHalfOpenSuccessNeed = 2                // successful probes required to close the circuit
+)
+
+// Health is the circuit breaker state of a channel
+type Health struct {
+	mutex sync.Mutex
+
+	state               string
+	results             []bool
+	cursor              int
+	consecutiveFailures int
+	probing             int
+	probeSuccesses      int
+	cooldown            time.Duration
+	openedAt            time.Time
+	lastError           string
+	lastErrorAt         time.Time
+	lastSuccessAt       time.Time
+}
+
+// HealthSnapshot is the read-only view of the channel health
+type HealthSnapshot struct {
+	State               string  `json:"state"`
+	Requests            int     `json:"requests"`
+	ErrorRate           float32 `json:"error_rate"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	LastError           string  `json:"last_error"`
+	LastErrorAt         int64   `json:"last_error_at"`
+	LastSuccessAt       int64   `json:"last_success_at"`
+	OpenUntil           int64   `json:"open_until"`
+}
+
+type HealthManager struct {
+	mutex sync.RWMutex
+	data  map[int]*Health
+}
+
+var HealthInstance = NewHealthManager()
+
+func NewHealthManager() *HealthManager {
+	return &HealthManager{
+		data: map[int]*Health{},
+	}
+}
+
+func NewHealth() *Health {
+	return &Health{
+		state:    HealthClosed,
+		results:  make([]bool, 0, healthWindowSize),
+		cooldown: healthOpenDuration,
+	}
+}
+
+// Get returns the health of the channel, creating it if not exists
+func (m *HealthManager) Get(id int) *Health {
+	m.mutex.RLock()
+	health, ok := m.data[id]
+	m.mutex.RUnlock()
+	if ok {
+		return health
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if health, ok = m.data[id]; !ok {
+		health = NewHealth()
+		m.data[id] = health
+	}
+	return health
+}
+
+// Reset clears the health state of the channel (e.g. after the channel is updated)
+func (m *HealthManager) Reset(id int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.data, id)
+}
+
+func (m *HealthManager) IsAvailable(id int) bool {
+	return m.Get(id).IsAvailable()
+}
+
+// Acquire returns whether the channel could be used for a request and whether a probe slot is held by it
+func (m *HealthManager) Acquire(id int) (bool, bool) {
+	return m.Get(id).Acquire()
+}
+
+// Release gives back the probe slot without recording a result (e.g. the request is stopped by the user)
+func (m *HealthManager) Release(id int, probe bool) {
+	m.Get(id).Release(probe)
+}
+
+func (m *HealthManager) Record(channel *Channel, err error, probe bool) {
+	if opened := m.Get(channel.GetId()).Record(err, probe); opened {
+		globals.Warn(fmt.Sprintf("[channel] circuit opened for channel %s (#%d): %s", channel.GetName(), channel.GetId(), err.Error()))
+	}
+}
+
+func (m *HealthManager) GetSnapshot(id int) HealthSnapshot {
+	return m.Get(id).GetSnapshot()
+}
+
+// refresh moves the circuit from open to half-open once the cool down has elapsed, lock must be held
+func (h *Health) refresh() {
+	if h.state == HealthOpen && time.Since(h.openedAt) >= h.cooldown {
+		h.state = HealthHalfOpen
+		h.probing = 0
+		h.probeSuccesses = 0
+	}
+}
+
+func (h *Health) GetState() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.refresh()
+	return h.state
+}
+
+// IsAvailable returns whether the channel could receive traffic, without reserving a probe
+func (h *Health) IsAvailable() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.refresh()
+	switch h.state {
+	case HealthOpen:
+		return false
+	case HealthHalfOpen:
+		return h.probing < healthHalfOpenProbeLimit
+	default:
+		return true
+	}
+}
+
+// Acquire reserves the channel for a request, half-open channels only accept limited probe traffic,
+// returns whether the request is allowed and whether it holds a probe slot
+func (h *Health) Acquire() (bool, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.refresh()
+	switch h.state {
+	case HealthOpen:
+		return false, false
+	case HealthHalfOpen:
+		if h.probing >= healthHalfOpenProbeLimit {
+			return false, false
+		}
+		h.probing++
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// Release gives back the probe slot held by the request
+func (h *Health) Release(probe bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.release(probe)
+}
+
+func (h *Health) release(probe bool) {
+	if probe && h.probing > 0 {
+		h.probing--
+	}
+}
+
+func (h *Health) push(success bool) {
+	if len(h.results) < healthWindowSize {
+		h.results = append(h.results, success)
+		return
+	}
+
+	h.results[h.cursor] = success
+	h.cursor = (h.cursor + 1) % healthWindowSize
+}
+
+func (h *Health) errorRate() float32 {
+	if len(h.results) == 0 {
+		return 0
+	}
+
+	failures := 0
+	for _, success := range h.results {
+		if !success {
+			failures++
+		}
+	}
+	return float32(failures) / float32(len(h.results))
+}
+
+func (h *Health) open() {
+	h.state = HealthOpen
+	h.openedAt = time.Now()
+	h.probing = 0
+	h.probeSuccesses = 0
+}
+
+func (h *Health) close() {
+	h.state = HealthClosed
+	h.results = h.results[:0]
+	h.cursor = 0
+	h.consecutiveFailures = 0
+	h.probing = 0
+	h.probeSuccesses = 0
+	h.cooldown = healthOpenDuration
+}
+
+// Record stores the result of a request and returns true if the circuit has been opened by it,
+// only the requests holding a probe slot (or successful requests sent while open) move a broken circuit
+func (h *Health) Record(err error, probe bool) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.release(probe)
+
+	success := err == nil
+	h.push(success)
+
+	if success {
+		h.consecutiveFailures = 0
+		h.lastSuccessAt = time.Now()
+	} else {
+		h.consecutiveFailures++
+		h.lastError = err.Error()
+		h.lastErrorAt = time.Now()
+	}
+
+	switch h.state {
+	case HealthOpen:
+		if success {
+			// every channel was broken and the request went through anyway, count it as a successful probe
+			h.state = HealthHalfOpen
+			h.probeSuccesses = 1
+			if h.probeSuccesses >= healthHalfOpenSuccessNeed {
+				h.close()
+			}
+		}
+	case HealthHalfOpen:
+		if !probe {
+			// result of a request which is not a probe (e.g. sent before the circuit was opened)
+			return false
+		}
+
+		if !success {
+			// probe failed, back off and open the circuit again
+			h.cooldown *= 2
+			if h.cooldown > healthMaxOpenDuration {
+				h.cooldown = healthMaxOpenDuration
+			}
+			h.open()
+			return true
+		}
+
+		if h.probeSuccesses++; h.probeSuccesses >= healthHalfOpenSuccessNeed {
+			h.close()
+		}
+	case HealthClosed:
+		if success {
+			return false
+		}
+
+		tripped := h.consecutiveFailures >= healthFailureThreshold ||
+			(len(h.results) >= healthMinRequests && h.errorRate() >= healthErrorRateThreshold)
+		if tripped {
+			h.open()
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Health) GetSnapshot() HealthSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.refresh()
+
+	snapshot := HealthSnapshot{
+		State:               h.state,
+		Requests:            len(h.results),
+		ErrorRate:           h.errorRate(),
+		ConsecutiveFailures: h.consecutiveFailures,
+		LastError:           h.lastError,
+	}
+
+	if !h.lastErrorAt.IsZero() {
+		snapshot.LastErrorAt = h.lastErrorAt.Unix()
+	}
+	if !h.lastSuccessAt.IsZero() {
+		snapshot.LastSuccessAt = h.lastSuccessAt.Unix()
+	}
+	if h.state == HealthOpen {
+		snapshot.OpenUntil = h.openedAt.Add(h.cooldown).Unix()
+	}
+
+	return snapshot
+}
@@ -0,0 +1,174 @@
+package channel
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errUpstream = errors.New("upstream error")
+
+// expire makes the cool down of the open circuit elapse
+func expire(h *Health) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.openedAt = time.Now().Add(-h.cooldown)
+}
+
+func TestHealthTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		results []error
+		state   string
+	}{
+		{
+			name:    "consecutive failures",
+			results: []error{nil, errUpstream, errUpstream, errUpstream, errUpstream, errUpstream},
+			state:   HealthOpen,
+		},
+		{
+			name:    "failures below threshold",
+			results: []error{errUpstream, errUpstream, errUpstream, errUpstream, nil},
+			state:   HealthClosed,
+		},
+		{
+			name: "error rate",
+			results: []error{
+				nil, errUpstream, nil, errUpstream, nil, errUpstream, nil, errUpstream, nil, errUpstream,
+			},
+			state: HealthOpen,
+		},
+		{
+			name: "error rate below min requests",
+			results: []error{
+				errUpstream, nil, errUpstream, nil, errUpstream, nil, errUpstream, nil, errUpstream,
+			},
+			state: HealthClosed,
+		},
+		{
+			name: "error rate below threshold",
+			results: []error{
+				errUpstream, nil, nil, errUpstream, nil, nil, errUpstream, nil, nil, errUpstream, nil, nil,
+			},
+			state: HealthClosed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := NewHealth()
+			for _, err := range c.results {
+				h.Record(err, false)
+			}
+
+			if state := h.GetState(); state != c.state {
+				t.Errorf("expected state %s, got %s", c.state, state)
+			}
+		})
+	}
+}
+
+func TestHealthHalfOpenProbe(t *testing.T) {
+	h := NewHealth()
+	for i := 0; i < healthFailureThreshold; i++ {
+		h.Record(errUpstream, false)
+	}
+
+	if allowed, _ := h.Acquire(); allowed {
+		t.Fatal("open circuit should not accept requests")
+	}
+
+	expire(h)
+	if state := h.GetState(); state != HealthHalfOpen {
+		t.Fatalf("expected state %s, got %s", HealthHalfOpen, state)
+	}
+
+	probes := 0
+	for i := 0; i < healthHalfOpenProbeLimit+2; i++ {
+		if allowed, probe := h.Acquire(); allowed && probe {
+			probes++
+		}
+	}
+	if probes != healthHalfOpenProbeLimit {
+		t.Fatalf("expected %d probes, got %d", healthHalfOpenProbeLimit, probes)
+	}
+
+	// a result of a request which is not a probe must not move the circuit
+	h.Record(nil, false)
+	if allowed, _ := h.Acquire(); allowed {
+		t.Fatal("probe slot should still be held")
+	}
+
+	// a stopped probe gives back its slot
+	h.Release(true)
+	if allowed, probe := h.Acquire(); !allowed || !probe {
+		t.Fatal("probe slot should be released")
+	}
+
+	for i := 0; i < healthHalfOpenSuccessNeed; i++ {
+		if i > 0 {
+			if allowed, probe := h.Acquire(); !allowed || !probe {
+				t.Fatal("expected a probe slot")
+			}
+		}
+		h.Record(nil, true)
+	}
+
+	if state := h.GetState(); state != HealthClosed {
+		t.Errorf("expected state %s, got %s", HealthClosed, state)
+	}
+}
+
+func TestHealthCooldown(t *testing.T) {
+	h := NewHealth()
+	for i := 0; i < healthFailureThreshold; i++ {
+		h.Record(errUpstream, false)
+	}
+
+	expected := healthOpenDuration
+	for i := 0; i < 10; i++ {
+		if h.cooldown != expected {
+			t.Fatalf("round %d: expected cool down %s, got %s", i, expected, h.cooldown)
+		}
+
+		expire(h)
+		if allowed, probe := h.Acquire(); !allowed || !probe {
+			t.Fatalf("round %d: expected a probe slot", i)
+		}
+		if opened := h.Record(errUpstream, true); !opened {
+			t.Fatalf("round %d: failed probe should open the circuit", i)
+		}
+
+		if expected *= 2; expected > healthMaxOpenDuration {
+			expected = healthMaxOpenDuration
+		}
+	}
+
+	if h.cooldown != healthMaxOpenDuration {
+		t.Errorf("expected cool down to be capped at %s, got %s", healthMaxOpenDuration, h.cooldown)
+	}
+
+	// closing the circuit resets the cool down
+	expire(h)
+	for i := 0; i < healthHalfOpenSuccessNeed; i++ {
+		h.Acquire()
+		h.Record(nil, true)
+	}
+	if h.cooldown != healthOpenDuration {
+		t.Errorf("expected cool down to be reset to %s, got %s", healthOpenDuration, h.cooldown)
+	}
+}
+
+func TestHealthOpenSuccess(t *testing.T) {
+	h := NewHealth()
+	for i := 0; i < healthFailureThreshold; i++ {
+		h.Record(errUpstream, false)
+	}
+
+	// request sent while every channel is broken (degraded ticker) and succeeded
+	h.Record(nil, false)
+	if state := h.GetState(); state != HealthHalfOpen {
+		t.Errorf("expected state %s, got %s", HealthHalfOpen, state)
+	}
+}
@@ -0,0 +1,222 @@
+package channel
+
+import (
+	"chat/connection"
+	"chat/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// concurrencyExpire is the safety expiration of the concurrency counters,
+// so that a crashed instance could not hold the slots forever
+const concurrencyExpire = 30 * time.Minute
+
+type RateLimit struct {
+	RPM         int64 `json:"rpm" mapstructure:"rpm"`                 // requests per minute
+	TPM         int64 `json:"tpm" mapstructure:"tpm"`                 // tokens per minute
+	Concurrency int64 `json:"concurrency" mapstructure:"concurrency"` // max concurrent streams
+}
+
+type reservation struct {
+	key   string
+	delta int64
+}
+
+// Reservation is the acquired rate limit slots of a channel (and one of its secrets)
+type Reservation struct {
+	cache       *redis.Client
+	counters    []reservation // rpm and tpm counters of the current window
+	tpm         []string
+	concurrency []string
+}
+
+func (l RateLimit) IsEmpty() bool {
+	return l.RPM <= 0 && l.TPM <= 0 && l.Concurrency <= 0
+}
+
+func getMinute() int64 {
+	return time.Now().Unix() / 60
+}
+
+func getChannelLimitKey(id int, name string) string {
+	return fmt.Sprintf(":channel-limit:%d:%s", id, name)
+}
+
+func getSecretLimitKey(id int, secret string, name string) string {
+	return fmt.Sprintf(":channel-limit:%d:%s:%s", id, utils.Md5Encrypt(secret), name)
+}
+
+// incrWithLimit increases the counter and rolls it back if the limit is exceeded,
+// a request is always allowed if it is the only usage of the window (e.g. a huge prompt under tpm)
+func incrWithLimit(cache *redis.Client, key string, delta int64, limit int64, expiration time.Duration) bool {
+	ctx := context.Background()
+	value, err := cache.IncrBy(ctx, key, delta).Result()
+	if err != nil {
+		// redis is not available, do not block the request
+		return true
+	}
+
+	if value == delta {
+		cache.Expire(ctx, key, expiration)
+	}
+
+	if value > limit && value != delta {
+		cache.DecrBy(ctx, key, delta)
+		return false
+	}
+
+	return true
+}
+
+func (r *Reservation) acquire(prefix func(name string) string, limit RateLimit, tokens int64) bool {
+	minute := getMinute()
+
+	if limit.Concurrency > 0 {
+		key := prefix("concurrency")
+		if !incrWithLimit(r.cache, key, 1, limit.Concurrency, concurrencyExpire) {
+			return false
+		}
+		r.concurrency = append(r.concurrency, key)
+	}
+
+	if limit.RPM > 0 {
+		key := prefix(fmt.Sprintf("rpm:%d", minute))
+		if !incrWithLimit(r.cache, key, 1, limit.RPM, time.Minute) {
+			return false
+		}
+		r.counters = append(r.counters, reservation{key: key, delta: 1})
+	}
+
+	if limit.TPM > 0 {
+		key := prefix(fmt.Sprintf("tpm:%d", minute))
+		if !incrWithLimit(r.cache, key, tokens, limit.TPM, time.Minute) {
+			return false
+		}
+		r.counters = append(r.counters, reservation{key: key, delta: tokens})
+		r.tpm = append(r.tpm, key)
+	}
+
+	return true
+}
+
+// rollback releases every counter acquired by the reservation
+func (r *Reservation) rollback() {
+	ctx := context.Background()
+	for _, item := range r.counters {
+		r.cache.DecrBy(ctx, item.key, item.delta)
+	}
+	for _, key := range r.concurrency {
+		r.cache.Decr(ctx, key)
+	}
+
+	r.counters, r.tpm, r.concurrency = nil, nil, nil
+}
+
+// Cancel gives back the reservation when the channel is not used at last
+func (r *Reservation) Cancel() {
+	if r == nil {
+		return
+	}
+
+	r.rollback()
+}
+
+// Release frees the concurrency slots and counts the output tokens into tpm
+func (r *Reservation) Release(tokens int64) {
+	if r == nil {
+		return
+	}
+
+	ctx := context.Background()
+	for _, key := range r.concurrency {
+		r.cache.Decr(ctx, key)
+	}
+
+	if tokens > 0 {
+		for _, key := range r.tpm {
+			r.cache.IncrBy(ctx, key, tokens)
+		}
+	}
+}
+
+func (c *Channel) GetLimit() RateLimit {
+	return c.Limit
+}
+
+func (c *Channel) GetSecretLimit() RateLimit {
+	return c.SecretLimit
+}
+
+// GetSecrets returns the non-empty secrets of the channel key pool
+func (c *Channel) GetSecrets() []string {
+	return utils.Filter(strings.Split(c.GetSecret(), "\n"), func(secret string) bool {
+		return len(strings.TrimSpace(secret)) > 0
+	})
+}
+
+// WithSecret returns a copy of the channel which only uses the given secret
+func (c *Channel) WithSecret(secret string) *Channel {
+	instance := *c
+	instance.Secret = secret
+	instance.CurrentSecret = nil
+	return &instance
+}
+
+// Reserve acquires the channel level and secret level rate limits for a request with the estimated tokens,
+// returns the channel bound to an available secret, or nil if the channel is rate limited or has no available secret
+func (c *Channel) Reserve(tokens int) (*Channel, *Reservation) {
+	secrets := SecretInstance.GetAvailableSecrets(c)
+	if len(secrets) == 0 && len(c.GetSecrets()) > 0 {
+		// every secret is quarantined or disabled
+		return nil, nil
+	}
+
+	cache := connection.Cache
+	if cache == nil || (c.GetLimit().IsEmpty() && c.GetSecretLimit().IsEmpty()) {
+		if len(secrets) == 0 {
+			return c, nil
+		}
+		return c.WithSecret(secrets[utils.Intn(len(secrets))]), nil
+	}
+
+	r := &Reservation{cache: cache}
+	if !r.acquire(func(name string) string {
+		return getChannelLimitKey(c.GetId(), name)
+	}, c.GetLimit(), int64(tokens)) {
+		r.rollback()
+		return nil, nil
+	}
+
+	if len(secrets) == 0 {
+		return c, r
+	}
+
+	// start from a random secret to spread the traffic
+	offset := utils.Intn(len(secrets))
+	if c.GetSecretLimit().IsEmpty() {
+		return c.WithSecret(secrets[offset]), r
+	}
+
+	for i := range secrets {
+		secret := secrets[(offset+i)%len(secrets)]
+
+		sub := &Reservation{cache: cache}
+		if sub.acquire(func(name string) string {
+			return getSecretLimitKey(c.GetId(), secret, name)
+		}, c.GetSecretLimit(), int64(tokens)) {
+			r.counters = append(r.counters, sub.counters...)
+			r.tpm = append(r.tpm, sub.tpm...)
+			r.concurrency = append(r.concurrency, sub.concurrency...)
+			return c.WithSecret(secret), r
+		}
+		sub.rollback()
+	}
+
+	// every secret is rate limited
+	r.rollback()
+	return nil, nil
+}
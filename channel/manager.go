@@ -5,6 +5,7 @@ import (
 	"chat/utils"
 	"errors"
 	"github.com/spf13/viper"
+	"strings"
 	"time"
 )
 
@@ -18,6 +19,9 @@ func InitManager() {
 	ChargeInstance = NewChargeManager()
 	SystemInstance = NewSystemConfig()
 	PlanInstance = NewPlanManager()
+	RoutingInstance = NewRoutingManager()
+
+	SecretInstance.Run()
 }
 
 func NewChannelManager() *Manager {
@@ -123,7 +127,7 @@ func (m *Manager) GetTicker(model, group string) *Ticker {
 		return nil
 	}
 
-	return NewTicker(m.HitSequence(model), group)
+	return NewTicker(m.HitSequence(model), model, group)
 }
 
 func (m *Manager) Len() int {
@@ -156,6 +160,7 @@ func (m *Manager) UpdateChannel(id int, channel *Channel) error {
 	for i, item := range m.Sequence {
 		if item.Id == id {
 			m.Sequence[i] = channel
+			HealthInstance.Reset(id)
 			return m.SaveConfig()
 		}
 	}
@@ -166,6 +171,8 @@ func (m *Manager) DeleteChannel(id int) error {
 	for i, item := range m.Sequence {
 		if item.Id == id {
 			m.Sequence = append(m.Sequence[:i], m.Sequence[i+1:]...)
+			HealthInstance.Reset(id)
+			SecretInstance.Reset(id)
 			return m.SaveConfig()
 		}
 	}
@@ -176,6 +183,18 @@ func (m *Manager) ActivateChannel(id int) error {
 	for i, item := range m.Sequence {
 		if item.Id == id {
 			m.Sequence[i].State = true
+			HealthInstance.Reset(id)
+			return m.SaveConfig()
+		}
+	}
+	return errors.New("channel not found")
+}
+
+// PurgeSecrets removes the quarantined and disabled secrets from the channel key pool
+func (m *Manager) PurgeSecrets(id int) error {
+	for _, item := range m.Sequence {
+		if item.Id == id {
+			item.Secret = strings.Join(SecretInstance.GetAvailableSecrets(item), "\n")
 			return m.SaveConfig()
 		}
 	}
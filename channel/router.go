@@ -13,6 +13,15 @@ func Register(app *gin.RouterGroup) {
 	app.GET("/admin/channel/delete/:id", DeleteChannel)
 	app.GET("/admin/channel/activate/:id", ActivateChannel)
 	app.GET("/admin/channel/deactivate/:id", DeactivateChannel)
+	app.GET("/admin/channel/test/:id", TestChannel)
+	app.GET("/admin/channel/models/:id", GetChannelModels)
+	app.GET("/admin/channel/secret/list/:id", GetSecretList)
+	app.POST("/admin/channel/secret/activate/:id", ActivateSecret)
+	app.POST("/admin/channel/secret/deactivate/:id", DeactivateSecret)
+	app.POST("/admin/channel/secret/check/:id", CheckSecret)
+	app.GET("/admin/channel/secret/purge/:id", PurgeSecrets)
+	app.GET("/admin/channel/routing/view", GetRoutingConfig)
+	app.POST("/admin/channel/routing/update", UpdateRoutingConfig)
 
 	app.GET("/admin/charge/list", GetChargeList)
 	app.POST("/admin/charge/set", SetCharge)
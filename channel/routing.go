@@ -0,0 +1,160 @@
+package channel
+
+import (
+	"chat/adapter"
+	"chat/utils"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	PriorityStrategy   = "priority" // default: strict priority + random weight
+	LatencyStrategy    = "latency"  // lowest observed time to first token
+	CostStrategy       = "cost"     // lowest cost per 1k tokens
+	LeastBusyStrategy  = "least-inflight"
+	RoundRobinStrategy = "round-robin"
+)
+
+var routingStrategies = []string{
+	PriorityStrategy, LatencyStrategy, CostStrategy, LeastBusyStrategy, RoundRobinStrategy,
+}
+
+type RoutingRule struct {
+	Models   []string `json:"models" mapstructure:"models"`
+	Groups   []string `json:"groups" mapstructure:"groups"`
+	Strategy string   `json:"strategy" mapstructure:"strategy"`
+}
+
+type RoutingManager struct {
+	Rules []RoutingRule `json:"rules" mapstructure:"rules"`
+
+	cursors map[string]int // round-robin cursors (model:group)
+	mutex   sync.Mutex
+}
+
+var RoutingInstance *RoutingManager
+
+// NewRoutingManager loads the rules from the top-level `routing` key, the `channel` key is the plain
+// list of channels in existing config files and could not carry the rules without breaking them
+func NewRoutingManager() *RoutingManager {
+	manager := &RoutingManager{}
+	if err := viper.UnmarshalKey("routing", manager); err != nil {
+		panic(err)
+	}
+
+	return manager
+}
+
+func (m *RoutingManager) SaveConfig() error {
+	viper.Set("routing", map[string]interface{}{
+		"rules": m.GetRules(),
+	})
+	return viper.WriteConfig()
+}
+
+func (m *RoutingManager) UpdateConfig(data *RoutingManager) error {
+	for _, rule := range data.Rules {
+		if !utils.Contains(rule.Strategy, routingStrategies) {
+			return fmt.Errorf("unknown routing strategy %s", rule.Strategy)
+		}
+	}
+
+	m.mutex.Lock()
+	m.Rules = data.Rules
+	m.mutex.Unlock()
+
+	return m.SaveConfig()
+}
+
+// GetRules returns the current rules, the slice is replaced on update and never modified in place
+func (m *RoutingManager) GetRules() []RoutingRule {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.Rules
+}
+
+func (r *RoutingRule) IsHit(model, group string) bool {
+	if len(r.Models) > 0 && !utils.Contains(model, r.Models) {
+		return false
+	}
+
+	if len(r.Groups) > 0 && !utils.Contains(group, r.Groups) {
+		return false
+	}
+
+	return true
+}
+
+// GetStrategy returns the strategy of the first matched rule, falls back to the priority strategy
+func (m *RoutingManager) GetStrategy(model, group string) string {
+	if m == nil {
+		return PriorityStrategy
+	}
+
+	for _, rule := range m.GetRules() {
+		if rule.IsHit(model, group) && len(rule.Strategy) > 0 {
+			return rule.Strategy
+		}
+	}
+
+	return PriorityStrategy
+}
+
+// NextCursor returns the round-robin cursor of the model in the group
+func (m *RoutingManager) NextCursor(model, group string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cursors == nil {
+		m.cursors = map[string]int{}
+	}
+
+	key := fmt.Sprintf("%s:%s", model, group)
+	current := m.cursors[key]
+	m.cursors[key] = current + 1
+
+	return current
+}
+
+// Arrange orders the sequence by the strategy, channels are tried one by one afterward
+func (m *RoutingManager) Arrange(seq Sequence, strategy, model, group string) Sequence {
+	stack := make(Sequence, len(seq))
+	copy(stack, seq)
+
+	switch strategy {
+	case LatencyStrategy:
+		sort.SliceStable(stack, func(i, j int) bool {
+			a, aOk := adapter.MetricsInstance.GetFirstToken(stack[i].GetId(), model)
+			b, bOk := adapter.MetricsInstance.GetFirstToken(stack[j].GetId(), model)
+			if aOk != bOk {
+				// unmeasured channels first, so that they could be measured
+				return !aOk
+			}
+			return a < b
+		})
+	case CostStrategy:
+		sort.SliceStable(stack, func(i, j int) bool {
+			a, b := stack[i].GetCost(), stack[j].GetCost()
+			if (a > 0) != (b > 0) {
+				// channels without cost are tried last
+				return a > 0
+			}
+			return a < b
+		})
+	case LeastBusyStrategy:
+		sort.SliceStable(stack, func(i, j int) bool {
+			return adapter.MetricsInstance.GetInFlight(stack[i].GetId()) < adapter.MetricsInstance.GetInFlight(stack[j].GetId())
+		})
+	case RoundRobinStrategy:
+		if len(stack) > 0 {
+			offset := m.NextCursor(model, group) % len(stack)
+			stack = append(stack[offset:], stack[:offset]...)
+		}
+	}
+
+	return stack
+}
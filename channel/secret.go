@@ -0,0 +1,304 @@
+package channel
+
+import (
+	"chat/adapter"
+	"chat/adapter/common"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SecretActive      = "active"
+	SecretQuarantined = "quarantined" // disabled by auth or billing errors, re-tested on schedule
+	SecretDisabled    = "disabled"    // disabled by the administrator, never re-tested
+)
+
+var (
+	secretCheckTick      = time.Minute
+	secretCheckInterval  = 10 * time.Minute // first re-test after the key is quarantined
+	secretMaxCheckPeriod = 6 * time.Hour    // re-test interval is doubled on each failure up to this value
+)
+
+// secretQuarantineErrors are the upstream error patterns meaning the key itself is unusable
+var secretQuarantineErrors = []string{
+	"status code: 401", "status code: 403",
+	"status: 401", "status: 403",
+	"invalid_api_key", "incorrect api key", "invalid api key", "api key not valid", "invalid x-api-key",
+	"authentication_error", "permission_error", "account_deactivated",
+	"insufficient_quota", "exceeded your current quota", "billing", "credit balance is too low",
+	"arrearage",
+}
+
+type SecretState struct {
+	Hash          string        `json:"hash"`
+	Secret        string        `json:"secret"` // masked secret
+	Status        string        `json:"status"`
+	LastError     string        `json:"last_error"`
+	LastErrorAt   int64         `json:"last_error_at"`
+	LastUsedAt    int64         `json:"last_used_at"`
+	QuarantinedAt int64         `json:"quarantined_at"`
+	NextCheckAt   int64         `json:"next_check_at"`
+	Requests      int64         `json:"requests"`
+	Failures      int64         `json:"failures"`
+	model         string        // model of the failed request, used for re-testing
+	period        time.Duration // current re-test interval
+}
+
+type SecretManager struct {
+	mutex sync.Mutex
+	data  map[int]map[string]*SecretState
+}
+
+var SecretInstance = NewSecretManager()
+
+func NewSecretManager() *SecretManager {
+	return &SecretManager{
+		data: map[int]map[string]*SecretState{},
+	}
+}
+
+func getSecretHash(secret string) string {
+	return utils.Md5Encrypt(secret)
+}
+
+// IsQuarantineError returns whether the error is caused by an invalid or out of quota key
+func IsQuarantineError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	content := strings.ToLower(err.Error())
+	for _, pattern := range secretQuarantineErrors {
+		if strings.Contains(content, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the state of the secret, lock must be held
+func (m *SecretManager) get(id int, secret string) *SecretState {
+	states, ok := m.data[id]
+	if !ok {
+		states = map[string]*SecretState{}
+		m.data[id] = states
+	}
+
+	hash := getSecretHash(secret)
+	state, ok := states[hash]
+	if !ok {
+		state = &SecretState{
+			Hash:   hash,
+			Secret: utils.ToSecret(secret),
+			Status: SecretActive,
+		}
+		states[hash] = state
+	}
+	return state
+}
+
+func (m *SecretManager) IsActive(id int, secret string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	states, ok := m.data[id]
+	if !ok {
+		return true
+	}
+
+	state, ok := states[getSecretHash(secret)]
+	return !ok || state.Status == SecretActive
+}
+
+// GetAvailableSecrets returns the secrets of the channel which are not quarantined or disabled
+func (m *SecretManager) GetAvailableSecrets(channel *Channel) []string {
+	return utils.Filter(channel.GetSecrets(), func(secret string) bool {
+		return m.IsActive(channel.GetId(), secret)
+	})
+}
+
+// Record counts the usage of the secret and quarantines it on auth or billing errors
+func (m *SecretManager) Record(channel *Channel, model string, secret *string, err error) {
+	if secret == nil || len(*secret) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state := m.get(channel.GetId(), *secret)
+	state.Requests++
+	state.LastUsedAt = time.Now().Unix()
+
+	if err == nil {
+		return
+	}
+
+	state.Failures++
+	state.LastError = err.Error()
+	state.LastErrorAt = time.Now().Unix()
+
+	if IsQuarantineError(err) && state.Status == SecretActive {
+		state.Status = SecretQuarantined
+		state.QuarantinedAt = time.Now().Unix()
+		state.period = secretCheckInterval
+		state.NextCheckAt = time.Now().Add(state.period).Unix()
+		state.model = model
+
+		globals.Warn(fmt.Sprintf("[channel] secret %s of channel %s (#%d) is quarantined: %s", state.Secret, channel.GetName(), channel.GetId(), state.LastError))
+	}
+}
+
+// ListSecrets returns the states of every secret in the channel key pool
+func (m *SecretManager) ListSecrets(channel *Channel) []SecretState {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return utils.Each(channel.GetSecrets(), func(secret string) SecretState {
+		return *m.get(channel.GetId(), secret)
+	})
+}
+
+func (m *SecretManager) findSecret(channel *Channel, hash string) (string, error) {
+	for _, secret := range channel.GetSecrets() {
+		if getSecretHash(secret) == hash {
+			return secret, nil
+		}
+	}
+	return "", fmt.Errorf("secret not found")
+}
+
+func (m *SecretManager) SetStatus(channel *Channel, hash string, status string) error {
+	secret, err := m.findSecret(channel, hash)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state := m.get(channel.GetId(), secret)
+	state.Status = status
+	state.NextCheckAt = 0
+	if status == SecretActive {
+		state.QuarantinedAt = 0
+	}
+	return nil
+}
+
+// Reset clears the key states of the channel
+func (m *SecretManager) Reset(id int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.data, id)
+}
+
+// TestSecret sends a minimal request through the channel with the given secret,
+// the probe is sent raw so that it is not counted into the routing metrics
+func TestSecret(channel *Channel, secret string, model string) error {
+	if len(model) == 0 {
+		if models := channel.GetHitModels(); len(models) > 0 {
+			model = models[0]
+		} else {
+			return fmt.Errorf("no model is available for channel %s", channel.GetName())
+		}
+	}
+
+	instance := channel.WithSecret(secret)
+
+	messages := []globals.Message{{Role: globals.User, Content: "hi"}}
+	buffer := utils.NewBuffer(model, messages, ChargeInstance.GetCharge(model))
+
+	return instance.ProcessError(adapter.NewRawChatRequest(instance, adaptercommon.CreateChatProps(&adaptercommon.ChatProps{
+		OriginalModel: model,
+		Message:       messages,
+		MaxTokens:     utils.ToPtr(1),
+	}, buffer), func(data *globals.Chunk) error {
+		buffer.WriteChunk(data)
+		return nil
+	}))
+}
+
+// Check re-tests the secret and re-activates it if the request succeeds
+func (m *SecretManager) Check(channel *Channel, hash string) error {
+	secret, err := m.findSecret(channel, hash)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	model := m.get(channel.GetId(), secret).model
+	m.mutex.Unlock()
+
+	err = TestSecret(channel, secret, model)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state := m.get(channel.GetId(), secret)
+	if err == nil {
+		if state.Status == SecretQuarantined {
+			globals.Info(fmt.Sprintf("[channel] secret %s of channel %s (#%d) is re-activated", state.Secret, channel.GetName(), channel.GetId()))
+
+			state.Status = SecretActive
+			state.QuarantinedAt = 0
+			state.NextCheckAt = 0
+		}
+		return nil
+	}
+
+	state.LastError = err.Error()
+	state.LastErrorAt = time.Now().Unix()
+
+	if state.Status == SecretQuarantined {
+		state.period *= 2
+		if state.period <= 0 {
+			state.period = secretCheckInterval
+		} else if state.period > secretMaxCheckPeriod {
+			state.period = secretMaxCheckPeriod
+		}
+		state.NextCheckAt = time.Now().Add(state.period).Unix()
+	}
+
+	return err
+}
+
+// getPendingChecks returns the quarantined secrets which need to be re-tested
+func (m *SecretManager) getPendingChecks(channel *Channel) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now().Unix()
+	var hashes []string
+	for _, secret := range channel.GetSecrets() {
+		state := m.get(channel.GetId(), secret)
+		if state.Status == SecretQuarantined && state.NextCheckAt <= now {
+			hashes = append(hashes, state.Hash)
+		}
+	}
+	return hashes
+}
+
+// Run re-tests the quarantined secrets on schedule
+func (m *SecretManager) Run() {
+	go func() {
+		for {
+			time.Sleep(secretCheckTick)
+
+			if ConduitInstance == nil {
+				continue
+			}
+
+			for _, channel := range ConduitInstance.GetActiveSequence() {
+				for _, hash := range m.getPendingChecks(channel) {
+					_ = m.Check(channel, hash)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package channel
+
+import (
+	"chat/adapter"
+	"chat/adapter/common"
+	"chat/globals"
+	"chat/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithAvailableSecret returns a copy of the channel bound to a random non-quarantined secret,
+// an error is returned if every secret of the key pool is quarantined or disabled
+func (c *Channel) WithAvailableSecret() (*Channel, error) {
+	if len(c.GetSecrets()) == 0 {
+		return c, nil
+	}
+
+	secrets := SecretInstance.GetAvailableSecrets(c)
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("no secret is available for channel %s (every secret is quarantined or disabled)", c.GetName())
+	}
+	return c.WithSecret(secrets[utils.Intn(len(secrets))]), nil
+}
+
+// MaskSecret hides the current secret of the channel in the error
+func (c *Channel) MaskSecret(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	secret := c.GetCurrentSecret()
+	if secret == nil || len(*secret) == 0 || !strings.Contains(err.Error(), *secret) {
+		return err
+	}
+	return errors.New(strings.Replace(err.Error(), *secret, utils.ToSecret(*secret), -1))
+}
+
+type TestResult struct {
+	Status     bool    `json:"status"`
+	Model      string  `json:"model"`
+	Latency    float64 `json:"latency"`     // total duration (ms)
+	FirstToken float64 `json:"first_token"` // time to first token (ms), 0 if no content is received
+	Content    string  `json:"content"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// RunChannelTest sends a minimal request through the channel factory and measures it,
+// the error is reported as is (without retrying or hiding the upstream, only the secret is masked) for the administrator
+func RunChannelTest(channel *Channel, model string) TestResult {
+	if len(model) == 0 {
+		if models := channel.GetHitModels(); len(models) > 0 {
+			model = models[0]
+		} else {
+			return TestResult{Error: fmt.Sprintf("no model is available for channel %s", channel.GetName())}
+		}
+	} else if !channel.IsHit(model) {
+		return TestResult{Model: model, Error: fmt.Sprintf("model %s is not supported by channel %s", model, channel.GetName())}
+	}
+
+	instance, err := channel.WithAvailableSecret()
+	if err != nil {
+		return TestResult{Model: model, Error: err.Error()}
+	}
+
+	messages := []globals.Message{{Role: globals.User, Content: "hi"}}
+	buffer := utils.NewBuffer(model, messages, ChargeInstance.GetCharge(model))
+	props := adaptercommon.CreateChatProps(&adaptercommon.ChatProps{
+		OriginalModel: model,
+		Message:       messages,
+		MaxTokens:     utils.ToPtr(16),
+	}, buffer)
+
+	start := time.Now()
+	var first time.Duration
+	err = instance.MaskSecret(adapter.NewRawChatRequest(instance, props, func(data *globals.Chunk) error {
+		if first == 0 && !data.IsEmpty() {
+			first = time.Since(start)
+		}
+		buffer.WriteChunk(data)
+		return nil
+	}))
+
+	result := TestResult{
+		Status:     err == nil,
+		Model:      model,
+		Latency:    float64(time.Since(start).Milliseconds()),
+		FirstToken: float64(first.Milliseconds()),
+		Content:    buffer.Read(),
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
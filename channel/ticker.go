@@ -2,7 +2,7 @@ package channel
 
 import "chat/utils"
 
-func NewTicker(seq Sequence, group string) *Ticker {
+func NewTicker(seq Sequence, model, group string) *Ticker {
 	stack := make(Sequence, 0)
 	for _, channel := range seq {
 		if channel.IsHitGroup(group) {
@@ -10,10 +10,27 @@ func NewTicker(seq Sequence, group string) *Ticker {
 		}
 	}
 
+	// skip the circuit broken channels, but keep them if every channel is broken (the ticker is degraded),
+	// so that the request is still sent to the upstream in order instead of failing immediately
+	healthy := utils.Filter(stack, func(channel *Channel) bool {
+		return HealthInstance.IsAvailable(channel.GetId())
+	})
+	degraded := len(healthy) == 0 && len(stack) > 0
+	if !degraded {
+		stack = healthy
+	}
+
 	stack.Sort()
 
+	strategy := RoutingInstance.GetStrategy(model, group)
+	if strategy != PriorityStrategy {
+		stack = RoutingInstance.Arrange(stack, strategy, model, group)
+	}
+
 	return &Ticker{
 		Sequence: stack,
+		Strategy: strategy,
+		Degraded: degraded,
 	}
 }
 
@@ -68,6 +85,13 @@ func (t *Ticker) Next() *Channel {
 		return nil
 	}
 
+	if t.Strategy != PriorityStrategy {
+		// sequence is already arranged by the strategy
+		channel := t.Sequence[t.Cursor]
+		t.Cursor++
+		return channel
+	}
+
 	priority := t.Sequence[t.Cursor].GetPriority()
 	channel := t.GetChannelByPriority(priority)
 	t.SkipPriority(priority)
@@ -18,6 +18,9 @@ type Channel struct {
 	State         bool                `json:"state" mapstructure:"state"`
 	Group         []string            `json:"group" mapstructure:"group"`
 	Proxy         globals.ProxyConfig `json:"proxy" mapstructure:"proxy"`
+	Cost          float32             `json:"cost" mapstructure:"cost"`                 // upstream cost per 1k tokens, used by the cost routing strategy
+	Limit         RateLimit           `json:"limit" mapstructure:"limit"`               // channel level rate limit
+	SecretLimit   RateLimit           `json:"secret_limit" mapstructure:"secret_limit"` // rate limit of each secret in the key pool
 	Reflect       *map[string]string  `json:"-"`
 	HitModels     *[]string           `json:"-"`
 	ExcludeModels *[]string           `json:"-"`
@@ -35,6 +38,8 @@ type Manager struct {
 type Ticker struct {
 	Sequence Sequence `json:"sequence"`
 	Cursor   int      `json:"cursor"`
+	Strategy string   `json:"strategy"`
+	Degraded bool     `json:"degraded"` // every channel is circuit broken, the health check is skipped
 }
 
 type Charge struct {
@@ -17,13 +17,42 @@ func NewChatRequest(group string, props *adaptercommon.ChatProps, hook globals.H
 	}
 
 	var err error
+	var limited bool
 	for !ticker.IsDone() {
 		if channel := ticker.Next(); channel != nil {
+			instance, reservation := channel.Reserve(getRequestTokens(props))
+			if instance == nil {
+				// rate limit is reached for the channel or all of its secrets are unavailable, try the next one
+				limited = true
+				globals.Debug(fmt.Sprintf("[channel] channel %s is unavailable (rate limited or secrets quarantined) for model %s", channel.GetName(), props.OriginalModel))
+				continue
+			}
+
+			allowed, probe := HealthInstance.Acquire(channel.GetId())
+			if !allowed && !ticker.Degraded {
+				// circuit is open or the half-open channel is already probing, try the next one
+				reservation.Cancel()
+				continue
+			}
+
 			props.MaxRetries = utils.ToPtr(channel.GetRetry())
-			if err = adapter.NewChatRequest(channel, props, hook); adapter.IsSkipError(err) {
+			err = adapter.NewChatRequest(instance, props, hook)
+			reservation.Release(getResponseTokens(props))
+
+			if adapter.IsSkipError(err) {
+				if err == nil {
+					HealthInstance.Record(channel, nil, probe)
+				} else {
+					// stopped by the user, the result says nothing about the channel
+					HealthInstance.Release(channel.GetId(), probe)
+				}
+				SecretInstance.Record(channel, props.OriginalModel, instance.GetCurrentSecret(), nil)
 				return err
 			}
 
+			HealthInstance.Record(channel, err, probe)
+			SecretInstance.Record(channel, props.OriginalModel, instance.GetCurrentSecret(), err)
+
 			globals.Warn(fmt.Sprintf("[channel] caught error %s for model %s at channel %s", err.Error(), props.OriginalModel, channel.GetName()))
 		}
 	}
@@ -31,12 +60,32 @@ func NewChatRequest(group string, props *adaptercommon.ChatProps, hook globals.H
 	globals.Info(fmt.Sprintf("[channel] channels are exhausted for model %s", props.OriginalModel))
 
 	if err == nil {
-		err = fmt.Errorf("channels are exhausted for model %s", props.OriginalModel)
+		if limited {
+			err = fmt.Errorf("no channel is available for model %s (rate limited or secrets quarantined), please try again later", props.OriginalModel)
+		} else {
+			err = fmt.Errorf("channels are exhausted for model %s", props.OriginalModel)
+		}
 	}
 
 	return err
 }
 
+func getRequestTokens(props *adaptercommon.ChatProps) int {
+	if props.Buffer == nil {
+		return utils.NumTokensFromMessages(props.Message, props.OriginalModel, false)
+	}
+
+	return props.Buffer.CountInputToken()
+}
+
+func getResponseTokens(props *adaptercommon.ChatProps) int64 {
+	if props.Buffer == nil {
+		return 0
+	}
+
+	return int64(props.Buffer.CountOutputToken(false))
+}
+
 func PreflightCache(cache *redis.Client, model string, hash string, buffer *utils.Buffer, hook globals.Hook) (int64, bool, error) {
 	if !utils.Contains(model, globals.CacheAcceptedModels) {
 		return 0, false, nil